@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+// updateChannel controls which version updateBinaries considers "latest"
+// for a given module.
+type updateChannel string
+
+const (
+	// channelStable only considers tagged, non-prerelease versions -
+	// goreinstall's original behavior.
+	channelStable updateChannel = "stable"
+	// channelPrerelease also considers tagged prerelease versions
+	// (ex: v1.2.3-rc.1), picking the highest semver overall.
+	channelPrerelease updateChannel = "prerelease"
+	// channelTip resolves mod@latest with "go list -m": the latest commit
+	// (ex: on main) for a module with no tagged releases, the same as
+	// "go install mod@latest" would, and the latest release otherwise.
+	channelTip updateChannel = "tip"
+)
+
+func parseChannel(s string) (updateChannel, error) {
+	switch updateChannel(s) {
+	case "", channelStable:
+		return channelStable, nil
+	case channelPrerelease:
+		return channelPrerelease, nil
+	case channelTip:
+		return channelTip, nil
+	}
+
+	return "", fmt.Errorf("goreinstall: --channel (%v) must be one of stable, prerelease, or tip", s)
+}
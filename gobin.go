@@ -16,7 +16,6 @@ import (
 
 	"github.com/simplylib/errgroup"
 	"github.com/simplylib/multierror"
-	"github.com/simplylib/ucheck/modproxy"
 	"golang.org/x/mod/module"
 	"golang.org/x/mod/semver"
 )
@@ -25,6 +24,7 @@ type goEnvVars struct {
 	GoBin     string `json:"GOBIN"`
 	GoPath    string `json:"GOPATH"`
 	GoVersion string `json:"GOVERSION"`
+	GoProxy   string `json:"GOPROXY"`
 }
 
 func getGoEnv(ctx context.Context, compilerPath string) (goEnvVars, error) {
@@ -138,6 +138,22 @@ type goBin struct {
 
 	compilerPath string
 
+	// targets to cross-compile each binary for, instead of the host
+	// GOOS/GOARCH. Empty means reinstall for the host only.
+	targets []target
+
+	// preserveFlags controls which of a binary's original build settings
+	// are reapplied when it is rebuilt. The zero value preserves nothing.
+	preserveFlags preserveFlagsSpec
+
+	// resolver looks up and memoizes latest module versions, shared between
+	// updateBinaries and the plan step.
+	resolver *versionResolver
+
+	// channel controls which version updateBinaries treats as "latest".
+	// The zero value behaves as channelStable.
+	channel updateChannel
+
 	force bool
 
 	workers int
@@ -157,28 +173,44 @@ func (gb *goBin) updateBinaries(ctx context.Context) error {
 				return fmt.Errorf("could not getGoBinaryInfo of (%v) due to error (%w)", path, err)
 			}
 
+			channel := gb.channel
+			if channel == "" {
+				channel = channelStable
+			}
+
 			if gb.verbose {
-				log.Printf("checking binary (%v) for updates", path)
+				log.Printf("checking binary (%v) for updates on channel (%v)", path, channel)
 			}
 
-			ver, err := modproxy.GetLatestVersion(ctx, info.Main.Path)
+			latestVersion, err := gb.resolver.GetVersionForChannel(ctx, info.Main.Path, channel)
 			if err != nil {
-				return fmt.Errorf("could not GetLatestVersion of (%v) due to error (%w)", path, err)
+				return fmt.Errorf("could not resolve latest version of (%v) due to error (%w)", path, err)
 			}
 
 			// if current version is not less than latest version
-			if semver.Compare(info.Main.Version, ver.Version) != -1 {
+			if semver.Compare(info.Main.Version, latestVersion) != -1 {
 				if gb.verbose {
 					log.Printf(
-						"skipping updating (%v) as version (%v) is greater than or equal to latest (%v)\n",
+						"skipping updating (%v): current version (%v) on channel (%v) is already >= candidate (%v)\n",
 						path,
 						info.Main.Version,
-						ver.Version,
+						channel,
+						latestVersion,
 					)
 				}
 				return nil
 			}
 
+			if gb.verbose {
+				log.Printf(
+					"updating (%v): current version (%v) is older than channel (%v) candidate (%v)\n",
+					path,
+					info.Main.Version,
+					channel,
+					latestVersion,
+				)
+			}
+
 			err = module.CheckPath(info.Path)
 			if err != nil {
 				return fmt.Errorf("module path (%v) is not a valid path for a go module with error (%w)", info.Path, err)
@@ -189,13 +221,21 @@ func (gb *goBin) updateBinaries(ctx context.Context) error {
 				return fmt.Errorf("could not escape go module path of (%v) error (%w)", info.Path, err)
 			}
 
+			escapedVersion, err := module.EscapeVersion(latestVersion)
+			if err != nil {
+				return fmt.Errorf("could not escape go module version of (%v) error (%w)", latestVersion, err)
+			}
+
+			args := append([]string{"install"}, buildFlagsFromSettings(info.Settings, gb.preserveFlags)...)
+			args = append(args, escapedModulePath+"@"+escapedVersion)
+
 			// #nosec G204
-			cmd := exec.CommandContext(ctx, gb.compilerPath, "install", escapedModulePath+"@latest")
+			cmd := exec.CommandContext(ctx, gb.compilerPath, args...)
 			cmd.Stderr = os.Stderr
 			cmd.Stdout = os.Stdout
 
 			if err = cmd.Run(); err != nil {
-				return fmt.Errorf("could not (go install %v@latest) error (%w)", info.Path, err)
+				return fmt.Errorf("could not (go install %v@%v) error (%w)", info.Path, latestVersion, err)
 			}
 
 			return nil
@@ -206,64 +246,115 @@ func (gb *goBin) updateBinaries(ctx context.Context) error {
 }
 
 func (gb *goBin) reinstallBinaries(ctx context.Context) error {
+	targets := gb.targets
+	if len(targets) == 0 {
+		targets = []target{{}}
+	}
+
 	var eg errgroup.Group
 	eg.SetLimit(gb.workers)
 
 	for _, path := range gb.paths {
 		path := path
-		eg.Go(func() error {
-			info, err := getGoBinaryInfo(ctx, path)
-			if err != nil {
-				return fmt.Errorf("could not getGoBinaryInfo of (%v) due to error (%w)", path, err)
-			}
 
-			goVersion := strings.Replace(info.GoVersion, "go", "v", 1)
-			goBinVersion := strings.Replace(gb.goBinVer, "go", "v", 1)
+		for _, t := range targets {
+			t := t
 
-			if semver.Compare(goVersion, goBinVersion) >= 0 && !gb.force {
-				if gb.verbose {
-					log.Printf(
-						"skipping (%v) as its version (%v) is equal or higher than the currently installed Go version (%v) and we weren't forced to reinstall\n",
-						path,
-						goVersion,
-						goBinVersion,
-					)
-				}
-				return nil
-			}
+			eg.Go(func() error {
+				return gb.reinstallOne(ctx, path, t)
+			})
+		}
+	}
 
-			if gb.verbose {
-				log.Printf("reinstalling (%v@%v)\n", path, info.Main.Version)
-			}
+	return eg.Wait()
+}
 
-			escapedModulePath, err := module.EscapePath(info.Path)
-			if err != nil {
-				return fmt.Errorf("could not escape go module path of (%v): error (%w)", info.Path, err)
-			}
+// reinstallOne reinstalls path for a single target. A zero-value target
+// means "the host GOOS/GOARCH, installed to the default GOBIN".
+func (gb *goBin) reinstallOne(ctx context.Context, path string, t target) error {
+	info, err := getGoBinaryInfo(ctx, path)
+	if err != nil {
+		return fmt.Errorf("could not getGoBinaryInfo of (%v) due to error (%w)", path, err)
+	}
 
-			escapedModuleVersion, err := module.EscapeVersion(info.Main.Version)
-			if err != nil {
-				return fmt.Errorf("could not escape go module version of (%v) error (%w)", info.Main.Version, err)
-			}
+	outPath := path
+	haveOutInfo := t.String() == ""
+	if t.String() != "" {
+		outPath = filepath.Join(filepath.Dir(path), t.dir(), filepath.Base(path))
+
+		// prefer the cross-compiled binary's own build info, if it already
+		// exists, so up-to-date decisions are made per-target. A target
+		// binary that does not exist yet is never up to date.
+		if targetInfo, err := getGoBinaryInfo(ctx, outPath); err == nil {
+			info = targetInfo
+			haveOutInfo = true
+		}
+	}
 
-			// #nosec G204
-			cmd := exec.CommandContext(ctx, gb.compilerPath, "install", escapedModulePath+"@"+escapedModuleVersion)
-			cmd.Stderr = os.Stderr
-			cmd.Stdout = os.Stdout
+	goVersion := strings.Replace(info.GoVersion, "go", "v", 1)
+	goBinVersion := strings.Replace(gb.goBinVer, "go", "v", 1)
 
-			err = cmd.Run()
-			if err != nil {
-				return fmt.Errorf(
-					"could not (go install %v@%v) due to error (%w)",
-					info.Path,
-					info.Main.Version,
-					err,
-				)
-			}
+	builtOS := settingValue(info.Settings, "GOOS")
+	builtArch := settingValue(info.Settings, "GOARCH")
 
-			return nil
-		})
+	targetMatches := haveOutInfo && (t.String() == "" || (builtOS == t.OS && builtArch == t.Arch))
+
+	if targetMatches && semver.Compare(goVersion, goBinVersion) >= 0 && !gb.force {
+		if gb.verbose {
+			log.Printf(
+				"skipping (%v) as its version (%v) is equal or higher than the currently installed Go version (%v) and we weren't forced to reinstall\n",
+				outPath,
+				goVersion,
+				goBinVersion,
+			)
+		}
+		return nil
 	}
 
-	return eg.Wait()
+	if gb.verbose {
+		if t.String() == "" {
+			log.Printf("reinstalling (%v@%v)\n", path, info.Main.Version)
+		} else {
+			log.Printf("reinstalling (%v@%v) for target (%v)\n", outPath, info.Main.Version, t)
+		}
+	}
+
+	escapedModulePath, err := module.EscapePath(info.Path)
+	if err != nil {
+		return fmt.Errorf("could not escape go module path of (%v): error (%w)", info.Path, err)
+	}
+
+	escapedModuleVersion, err := module.EscapeVersion(info.Main.Version)
+	if err != nil {
+		return fmt.Errorf("could not escape go module version of (%v) error (%w)", info.Main.Version, err)
+	}
+
+	args := append([]string{"install"}, buildFlagsFromSettings(info.Settings, gb.preserveFlags)...)
+	args = append(args, escapedModulePath+"@"+escapedModuleVersion)
+
+	// #nosec G204
+	cmd := exec.CommandContext(ctx, gb.compilerPath, args...)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+
+	if t.String() != "" {
+		cmd.Env = append(
+			os.Environ(),
+			"GOOS="+t.OS,
+			"GOARCH="+t.Arch,
+			"GOBIN="+filepath.Join(filepath.Dir(path), t.dir()),
+		)
+	}
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf(
+			"could not (go install %v@%v) for target (%v) due to error (%w)",
+			info.Path,
+			info.Main.Version,
+			t,
+			err,
+		)
+	}
+
+	return nil
 }
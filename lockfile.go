@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/simplylib/errgroup"
+	"github.com/simplylib/multierror"
+	"github.com/simplylib/ucheck/modproxy"
+	"golang.org/x/mod/module"
+)
+
+// lockEntry pins a single GOBIN binary to the module version, Go version and
+// module zip hash it was built from.
+type lockEntry struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	Sum       string `json:"sum"`
+}
+
+// lockfile is the on-disk representation of goreinstall.lock, keyed by the
+// binary's file name in GOBIN.
+type lockfile struct {
+	Binaries map[string]lockEntry `json:"binaries"`
+}
+
+// defaultLockfilePath returns $GOBIN/goreinstall.lock, or $GOPATH/bin/goreinstall.lock
+// if GOBIN is unset.
+func defaultLockfilePath(goEnv goEnvVars) string {
+	if goEnv.GoBin != "" {
+		return filepath.Join(goEnv.GoBin, "goreinstall.lock")
+	}
+
+	return filepath.Join(goEnv.GoPath, "bin", "goreinstall.lock")
+}
+
+func readLockfile(path string) (lockfile, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return lockfile{}, fmt.Errorf("could not read lockfile (%v) due to error (%w)", path, err)
+	}
+
+	lf := lockfile{}
+	if err = json.Unmarshal(b, &lf); err != nil {
+		return lockfile{}, fmt.Errorf("could not parse lockfile (%v) due to error (%w)", path, err)
+	}
+
+	return lf, nil
+}
+
+func writeLockfile(path string, lf lockfile) error {
+	b, err := json.MarshalIndent(lf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not marshal lockfile (%w)", err)
+	}
+
+	// #nosec G306
+	if err = os.WriteFile(filepath.Clean(path), b, 0o644); err != nil {
+		return fmt.Errorf("could not write lockfile (%v) due to error (%w)", path, err)
+	}
+
+	return nil
+}
+
+// freeze writes a lockfile pinning every binary in gb.paths to its current
+// module, version, Go version, and module sum.
+func (gb *goBin) freeze(ctx context.Context, lockfilePath string) error {
+	lf := lockfile{Binaries: make(map[string]lockEntry, len(gb.paths))}
+
+	var eg errgroup.Group
+	eg.SetLimit(gb.workers)
+
+	var mu sync.Mutex
+
+	for _, path := range gb.paths {
+		path := path
+
+		eg.Go(func() error {
+			info, err := getGoBinaryInfo(ctx, path)
+			if err != nil {
+				return fmt.Errorf("could not getGoBinaryInfo of (%v) due to error (%w)", path, err)
+			}
+
+			entry := lockEntry{
+				Module:    info.Main.Path,
+				Version:   info.Main.Version,
+				GoVersion: info.GoVersion,
+				Sum:       info.Main.Sum,
+			}
+
+			mu.Lock()
+			lf.Binaries[filepath.Base(path)] = entry
+			mu.Unlock()
+
+			if gb.verbose {
+				log.Printf("froze (%v) as (%v@%v)\n", path, entry.Module, entry.Version)
+			}
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return fmt.Errorf("could not freeze binaries (%w)", err)
+	}
+
+	return writeLockfile(lockfilePath, lf)
+}
+
+// sync reads a lockfile and runs (go install module@version) for every entry
+// whose on-disk binary does not already match, verifying the resulting
+// binary's module sum against both the lockfile and the module proxy before
+// accepting it.
+func (gb *goBin) sync(ctx context.Context, lockfilePath, binaryDir string) error {
+	lf, err := readLockfile(lockfilePath)
+	if err != nil {
+		return fmt.Errorf("could not readLockfile (%w)", err)
+	}
+
+	var eg errgroup.Group
+	eg.SetLimit(gb.workers)
+
+	for name, entry := range lf.Binaries {
+		name, entry := name, entry
+
+		eg.Go(func() error {
+			return gb.syncOne(ctx, filepath.Join(binaryDir, name), entry)
+		})
+	}
+
+	return eg.Wait()
+}
+
+func (gb *goBin) syncOne(ctx context.Context, path string, entry lockEntry) error {
+	if info, err := getGoBinaryInfo(ctx, path); err == nil {
+		if info.Main.Path == entry.Module && info.Main.Version == entry.Version {
+			if gb.verbose {
+				log.Printf("skipping (%v) as it already matches the lockfile\n", path)
+			}
+
+			return nil
+		}
+	}
+
+	escapedModulePath, err := module.EscapePath(entry.Module)
+	if err != nil {
+		return fmt.Errorf("could not escape go module path of (%v) error (%w)", entry.Module, err)
+	}
+
+	escapedModuleVersion, err := module.EscapeVersion(entry.Version)
+	if err != nil {
+		return fmt.Errorf("could not escape go module version of (%v) error (%w)", entry.Version, err)
+	}
+
+	zipHash, err := modproxy.GetZipHash(ctx, entry.Module, entry.Version)
+	if err != nil {
+		return fmt.Errorf("could not GetZipHash of (%v@%v) due to error (%w)", entry.Module, entry.Version, err)
+	}
+
+	if zipHash != entry.Sum {
+		return fmt.Errorf(
+			"goreinstall: module proxy zip hash (%v) for (%v@%v) does not match lockfile sum (%v), refusing to sync",
+			zipHash,
+			entry.Module,
+			entry.Version,
+			entry.Sum,
+		)
+	}
+
+	if gb.verbose {
+		log.Printf("syncing (%v) to (%v@%v)\n", path, entry.Module, entry.Version)
+	}
+
+	// #nosec G204
+	cmd := exec.CommandContext(ctx, gb.compilerPath, "install", escapedModulePath+"@"+escapedModuleVersion)
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("could not (go install %v@%v) due to error (%w)", entry.Module, entry.Version, err)
+	}
+
+	info, err := getGoBinaryInfo(ctx, path)
+	if err != nil {
+		return fmt.Errorf("could not getGoBinaryInfo of freshly installed (%v) due to error (%w)", path, err)
+	}
+
+	if err = verifyAgainstLockEntry(info, entry); err != nil {
+		if removeErr := os.Remove(filepath.Clean(path)); removeErr != nil {
+			return multierror.Append(err, fmt.Errorf("could not remove mismatched binary (%v) due to error (%w)", path, removeErr))
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func verifyAgainstLockEntry(info *buildinfo.BuildInfo, entry lockEntry) error {
+	if info.Main.Version != entry.Version {
+		return fmt.Errorf(
+			"goreinstall: installed version (%v) of (%v) does not match lockfile version (%v)",
+			info.Main.Version,
+			info.Main.Path,
+			entry.Version,
+		)
+	}
+
+	if info.Main.Sum != "" && entry.Sum != "" && info.Main.Sum != entry.Sum {
+		return fmt.Errorf(
+			"goreinstall: installed sum (%v) of (%v@%v) does not match lockfile sum (%v)",
+			info.Main.Sum,
+			info.Main.Path,
+			info.Main.Version,
+			entry.Sum,
+		)
+	}
+
+	return nil
+}
@@ -30,6 +30,24 @@ func run() error {
 	exclude := flag.String("e", "", "list of binaries to exclude from running against ex: \"goreinstall,gitsum\"")
 	compiler := flag.String("c", "go", "name of binary to use instead of (go) for go commands")
 	force := flag.Bool("f", false, "forcefully reinstall binaries even if not required")
+	dryRun := flag.Bool("n", false, "print a plan of what would be done without installing or updating anything")
+	flag.BoolVar(dryRun, "dry-run", false, "alias of -n")
+	asJSON := flag.Bool("json", false, "print the dry-run plan as JSON instead of a table (implies -n)")
+	freeze := flag.Bool("freeze", false, "write a lockfile pinning every binary in GOBIN to its current module, version and Go version")
+	syncLock := flag.Bool("sync", false, "install binaries pinned in a lockfile, verifying each against the module proxy")
+	lockfilePath := flag.String("lockfile", "", "path to the goreinstall lockfile (default $GOBIN/goreinstall.lock)")
+	var targets targetsFlag
+	flag.Var(&targets, "target", "os/arch to cross-compile for, ex: linux/amd64 (repeatable, or comma-separated)")
+	preserveFlags := flag.String(
+		"preserve-flags",
+		"auto",
+		"which original build flags (-trimpath, -buildvcs, -tags, ...) to reapply on reinstall: auto|off|tags,trimpath,...",
+	)
+	channel := flag.String(
+		"channel",
+		string(channelStable),
+		"which versions -u considers an update: stable|prerelease|tip",
+	)
 
 	flag.CommandLine.Usage = func() {
 		fmt.Fprint(flag.CommandLine.Output(),
@@ -38,7 +56,12 @@ func run() error {
 			"Ex: "+os.Args[0]+" -a                // reinstall all binaries in GOBIN\n",
 			"Ex: "+os.Args[0]+" -a -u             // reinstall all binaries and update if needed\n",
 			"Ex: "+os.Args[0]+" goreinstall -u    // reinstall goreinstall and update if needed\n",
-			"Ex: "+os.Args[0]+" -a -c \"go1.20rc2\" // reinstall all binaries if needed using go1.20rc2 command",
+			"Ex: "+os.Args[0]+" -a -c \"go1.20rc2\" // reinstall all binaries if needed using go1.20rc2 command\n",
+			"Ex: "+os.Args[0]+" -a -u -n           // print a plan of what -a -u would do without doing it\n",
+			"Ex: "+os.Args[0]+" -a --freeze        // write a goreinstall.lock pinning GOBIN as it is now\n",
+			"Ex: "+os.Args[0]+" --sync             // reinstall binaries pinned in goreinstall.lock\n",
+			"Ex: "+os.Args[0]+" -a --target=linux/amd64,darwin/arm64 // reinstall all binaries for both targets\n",
+			"Ex: "+os.Args[0]+" -a -u --channel=tip // update all binaries on the tip channel",
 			"\nFlags:\n",
 		)
 		flag.CommandLine.PrintDefaults()
@@ -66,6 +89,38 @@ func run() error {
 		return listCommand(ctx, os.Args)
 	}
 
+	if *freeze || *syncLock {
+		goEnv, err := getGoEnv(ctx, *compiler)
+		if err != nil {
+			return fmt.Errorf("could not get GoEnv (%w)", err)
+		}
+
+		path := *lockfilePath
+		if path == "" {
+			path = defaultLockfilePath(goEnv)
+		}
+
+		if *freeze {
+			paths, err := getAllGoBins(goEnv, *verbose)
+			if err != nil {
+				return fmt.Errorf("could not getAllGoBins (%w)", err)
+			}
+
+			gb := goBin{paths: paths, workers: *maxWorkers, compilerPath: *compiler, verbose: *verbose}
+
+			return gb.freeze(ctx, path)
+		}
+
+		binaryDir := goEnv.GoBin
+		if binaryDir == "" {
+			binaryDir = filepath.Join(goEnv.GoPath, "bin")
+		}
+
+		gb := goBin{workers: *maxWorkers, compilerPath: *compiler, verbose: *verbose}
+
+		return gb.sync(ctx, path, binaryDir)
+	}
+
 	if flag.NArg() == 0 && !*all {
 		log.SetOutput(os.Stderr)
 		log.Print("Expected at least 1 package\n\n")
@@ -73,16 +128,16 @@ func run() error {
 		return errors.New("")
 	}
 
+	goEnv, err := getGoEnv(ctx, *compiler)
+	if err != nil {
+		return fmt.Errorf("could not get GoEnv (%w)", err)
+	}
+
 	var (
 		goBinVer string
 		paths    []string
 	)
 	if *all {
-		goEnv, err := getGoEnv(ctx, *compiler)
-		if err != nil {
-			return fmt.Errorf("could not get GoEnv (%w)", err)
-		}
-
 		goBinVer = goEnv.GoVersion
 
 		paths, err = getAllGoBins(goEnv, *verbose)
@@ -124,13 +179,35 @@ func run() error {
 		}
 	}
 
+	preserveFlagsSpec, err := parsePreserveFlags(*preserveFlags)
+	if err != nil {
+		return fmt.Errorf("could not parse --preserve-flags (%w)", err)
+	}
+
+	updateChan, err := parseChannel(*channel)
+	if err != nil {
+		return fmt.Errorf("could not parse --channel (%w)", err)
+	}
+
 	gb := goBin{
-		paths:        paths,
-		workers:      *maxWorkers,
-		compilerPath: *compiler,
-		force:        *force,
-		verbose:      *verbose,
-		goBinVer:     goBinVer,
+		paths:         paths,
+		workers:       *maxWorkers,
+		compilerPath:  *compiler,
+		force:         *force,
+		verbose:       *verbose,
+		goBinVer:      goBinVer,
+		targets:       targets,
+		preserveFlags: preserveFlagsSpec,
+		channel:       updateChan,
+		resolver:      newVersionResolver(goEnv.GoProxy, *compiler, *maxWorkers),
+	}
+
+	if *asJSON {
+		*dryRun = true
+	}
+
+	if *dryRun {
+		return gb.planCommand(ctx, os.Stdout, *asJSON)
 	}
 
 	// update binaries before attempting to reinstall those binaries.
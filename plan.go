@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/simplylib/errgroup"
+	"golang.org/x/mod/semver"
+)
+
+// planEntry describes what would happen to a single binary if goreinstall
+// were run without -n/--dry-run.
+type planEntry struct {
+	Path           string `json:"path"`
+	Module         string `json:"module"`
+	CurrentVersion string `json:"current_version"`
+	LatestVersion  string `json:"latest_version"`
+	CurrentGo      string `json:"current_go"`
+	TargetGo       string `json:"target_go"`
+	Action         string `json:"action"`
+	Reason         string `json:"reason"`
+}
+
+// plan resolves every binary in gb.paths against modproxy and the Go version
+// goreinstall was invoked with, without installing or updating anything.
+func (gb *goBin) plan(ctx context.Context) ([]planEntry, error) {
+	entries := make([]planEntry, len(gb.paths))
+
+	var eg errgroup.Group
+	eg.SetLimit(gb.workers)
+
+	for i, path := range gb.paths {
+		i, path := i, path
+
+		eg.Go(func() error {
+			entry, err := gb.planOne(ctx, path)
+			if err != nil {
+				return fmt.Errorf("could not plan (%v) due to error (%w)", path, err)
+			}
+
+			entries[i] = entry
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (gb *goBin) planOne(ctx context.Context, path string) (planEntry, error) {
+	info, err := getGoBinaryInfo(ctx, path)
+	if err != nil {
+		return planEntry{}, fmt.Errorf("could not getGoBinaryInfo of (%v) due to error (%w)", path, err)
+	}
+
+	entry := planEntry{
+		Path:           path,
+		Module:         info.Main.Path,
+		CurrentVersion: info.Main.Version,
+		CurrentGo:      info.GoVersion,
+		TargetGo:       gb.goBinVer,
+	}
+
+	channel := gb.channel
+	if channel == "" {
+		channel = channelStable
+	}
+
+	latestVersion, err := gb.resolver.GetVersionForChannel(ctx, info.Main.Path, channel)
+	if err != nil {
+		return planEntry{}, fmt.Errorf("could not resolve latest version of (%v) due to error (%w)", path, err)
+	}
+	entry.LatestVersion = latestVersion
+
+	if semver.Compare(info.Main.Version, latestVersion) == -1 {
+		entry.Action = "update"
+		entry.Reason = fmt.Sprintf("newer version (%v) is available", latestVersion)
+
+		return entry, nil
+	}
+
+	goVersion := strings.Replace(info.GoVersion, "go", "v", 1)
+	goBinVersion := strings.Replace(gb.goBinVer, "go", "v", 1)
+
+	if semver.Compare(goVersion, goBinVersion) >= 0 && !gb.force {
+		entry.Action = "skip"
+		entry.Reason = "up to date"
+
+		return entry, nil
+	}
+
+	entry.Action = "reinstall"
+	if gb.force {
+		entry.Reason = "forced reinstall (-f)"
+	} else {
+		entry.Reason = fmt.Sprintf("built with Go (%v) which is older than the installed Go (%v)", info.GoVersion, gb.goBinVer)
+	}
+
+	return entry, nil
+}
+
+// printPlanJSON writes entries to w as a JSON array.
+func printPlanJSON(w io.Writer, entries []planEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(entries); err != nil {
+		return fmt.Errorf("could not encode plan as JSON (%w)", err)
+	}
+
+	return nil
+}
+
+// printPlanTable writes entries to w as a human-readable table.
+func printPlanTable(w io.Writer, entries []planEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "PATH\tMODULE\tCURRENT\tLATEST\tACTION\tREASON")
+
+	for i := range entries {
+		fmt.Fprintf(
+			tw,
+			"%v\t%v\t%v\t%v\t%v\t%v\n",
+			entries[i].Path,
+			entries[i].Module,
+			entries[i].CurrentVersion,
+			entries[i].LatestVersion,
+			entries[i].Action,
+			entries[i].Reason,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("could not flush plan table (%w)", err)
+	}
+
+	return nil
+}
+
+// planCommand runs the dry-run/plan step and prints the result, either as a
+// human table or as JSON depending on asJSON.
+func (gb *goBin) planCommand(ctx context.Context, w io.Writer, asJSON bool) error {
+	entries, err := gb.plan(ctx)
+	if err != nil {
+		return fmt.Errorf("could not plan (%w)", err)
+	}
+
+	if gb.verbose {
+		log.Printf("planned (%v) binaries\n", len(entries))
+	}
+
+	if asJSON {
+		return printPlanJSON(w, entries)
+	}
+
+	return printPlanTable(w, entries)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// preserveFlagsSpec controls which of a binary's original build settings
+// (debug.BuildInfo.Settings) are reapplied when updateBinaries or
+// reinstallBinaries rebuild it.
+type preserveFlagsSpec struct {
+	// off disables preserving any flags, matching the behavior before
+	// --preserve-flags existed.
+	off bool
+
+	// keys is the set of setting keys to reapply, ex: "-trimpath".
+	keys map[string]bool
+}
+
+// safePreserveKeys are build settings safe to blindly reapply when
+// rebuilding a binary. Unlike -ldflags/-gcflags/-asmflags, none of these can
+// smuggle an absolute path or arbitrary linker/compiler directive from the
+// machine that originally built the binary, so they're included in "auto".
+var safePreserveKeys = map[string]bool{
+	"-trimpath": true,
+	"-buildvcs": true,
+	"-tags":     true,
+}
+
+// parsePreserveFlags parses the --preserve-flags value: "auto" (the safe
+// defaults above), "off" (preserve nothing, the old behavior), or an
+// explicit comma-separated list of setting names (with or without a leading
+// dash) such as "tags,trimpath".
+func parsePreserveFlags(s string) (preserveFlagsSpec, error) {
+	switch s {
+	case "", "auto":
+		return preserveFlagsSpec{keys: safePreserveKeys}, nil
+	case "off":
+		return preserveFlagsSpec{off: true}, nil
+	}
+
+	keys := make(map[string]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(name, "-") {
+			name = "-" + name
+		}
+
+		keys[name] = true
+	}
+
+	if len(keys) == 0 {
+		return preserveFlagsSpec{}, fmt.Errorf("goreinstall: --preserve-flags (%v) did not name any flags", s)
+	}
+
+	return preserveFlagsSpec{keys: keys}, nil
+}
+
+// buildFlagsFromSettings recovers the "go install" flags a binary was
+// originally built with, limited to the settings selected by pf. Unknown or
+// unsafe settings (ex: -ldflags with an absolute path baked in) are dropped
+// unless explicitly named in pf.
+func buildFlagsFromSettings(settings []debug.BuildSetting, pf preserveFlagsSpec) []string {
+	if pf.off {
+		return nil
+	}
+
+	var flags []string
+
+	for i := range settings {
+		key, value := settings[i].Key, settings[i].Value
+		if !pf.keys[key] || value == "" {
+			continue
+		}
+
+		switch key {
+		case "-trimpath", "-buildvcs":
+			if value != "true" {
+				continue
+			}
+			flags = append(flags, key)
+		default:
+			flags = append(flags, key+"="+value)
+		}
+	}
+
+	return flags
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// target is a GOOS/GOARCH pair to cross-compile a binary for.
+type target struct {
+	OS   string
+	Arch string
+}
+
+func (t target) String() string {
+	if t.OS == "" && t.Arch == "" {
+		return ""
+	}
+
+	return t.OS + "/" + t.Arch
+}
+
+// dir is the subdirectory under GOBIN cross-compiled binaries for t are
+// written to, ex: "linux_amd64".
+func (t target) dir() string {
+	return t.OS + "_" + t.Arch
+}
+
+func parseTarget(s string) (target, error) {
+	osArch := strings.SplitN(s, "/", 2)
+	if len(osArch) != 2 || osArch[0] == "" || osArch[1] == "" {
+		return target{}, fmt.Errorf("goreinstall: target (%v) is not of the form os/arch, ex: linux/amd64", s)
+	}
+
+	return target{OS: osArch[0], Arch: osArch[1]}, nil
+}
+
+// targetsFlag implements flag.Value, accumulating repeated and/or
+// comma-separated --target=os/arch[,os/arch...] flags.
+type targetsFlag []target
+
+func (tf *targetsFlag) String() string {
+	if tf == nil {
+		return ""
+	}
+
+	parts := make([]string, len(*tf))
+	for i := range *tf {
+		parts[i] = (*tf)[i].String()
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (tf *targetsFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		t, err := parseTarget(part)
+		if err != nil {
+			return err
+		}
+
+		*tf = append(*tf, t)
+	}
+
+	return nil
+}
+
+// settingValue returns the value of key in settings, or "" if not present.
+func settingValue(settings []debug.BuildSetting, key string) string {
+	for i := range settings {
+		if settings[i].Key == key {
+			return settings[i].Value
+		}
+	}
+
+	return ""
+}
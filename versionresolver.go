@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simplylib/multierror"
+	"github.com/simplylib/ucheck/modproxy"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// versionResolver resolves and memoizes the latest version of a module,
+// sharing one keep-alive *http.Client and a bounded worker pool across every
+// caller (updateBinaries, the plan step, ...) for the lifetime of a run, so
+// a module referenced by several binaries is looked up again only while its
+// first lookup is still running; concurrent lookups of one module are not
+// merged.
+type versionResolver struct {
+	client *http.Client
+
+	// proxies is the GOPROXY fallback chain, same semantics as the go
+	// command: each entry is tried in order until one resolves the module,
+	// "direct" falls back to modproxy's own resolution, and "off" stops
+	// resolution entirely.
+	proxies []string
+
+	// compilerPath is used for channelTip lookups, which need "go list"
+	// rather than the raw module proxy protocol.
+	compilerPath string
+
+	sem chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]resolvedVersion
+}
+
+type resolvedVersion struct {
+	version string
+	err     error
+}
+
+// newVersionResolver builds a versionResolver from a GOPROXY value (as
+// returned by "go env -json"), the compiler to use for channelTip lookups,
+// and a worker limit shared with the rest of goreinstall's operations.
+func newVersionResolver(goProxy, compilerPath string, workers int) *versionResolver {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &versionResolver{
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        workers,
+				MaxIdleConnsPerHost: workers,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			Timeout: 30 * time.Second,
+		},
+		proxies:      splitGoProxy(goProxy),
+		compilerPath: compilerPath,
+		sem:          make(chan struct{}, workers),
+		cache:        make(map[string]resolvedVersion),
+	}
+}
+
+// splitGoProxy parses a GOPROXY value into its fallback chain. The go
+// command distinguishes ',' (fall through on any error) from '|' (fall
+// through only on a 404/410); goreinstall does not make that distinction
+// today and simply tries every entry in order.
+func splitGoProxy(goProxy string) []string {
+	goProxy = strings.TrimSpace(goProxy)
+	if goProxy == "" {
+		return []string{"https://proxy.golang.org", "direct"}
+	}
+
+	fields := strings.FieldsFunc(goProxy, func(r rune) bool {
+		return r == ',' || r == '|'
+	})
+
+	proxies := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			proxies = append(proxies, f)
+		}
+	}
+
+	return proxies
+}
+
+// GetLatestVersion resolves the latest stable (tagged, non-prerelease)
+// version of mod, memoizing the result for the lifetime of vr and
+// serializing lookups through a bounded worker pool shared across every
+// caller. It is equivalent to GetVersionForChannel(ctx, mod, channelStable).
+func (vr *versionResolver) GetLatestVersion(ctx context.Context, mod string) (string, error) {
+	return vr.GetVersionForChannel(ctx, mod, channelStable)
+}
+
+// GetVersionForChannel resolves mod's latest version on ch, memoizing the
+// result per (mod, channel) for the lifetime of vr and serializing lookups
+// through a bounded worker pool shared across every caller.
+func (vr *versionResolver) GetVersionForChannel(ctx context.Context, mod string, ch updateChannel) (string, error) {
+	key := string(ch) + ":" + mod
+
+	if version, err, ok := vr.cached(key); ok {
+		return version, err
+	}
+
+	select {
+	case vr.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-vr.sem }()
+
+	// another goroutine may have resolved key while we waited for a slot
+	if version, err, ok := vr.cached(key); ok {
+		return version, err
+	}
+
+	var (
+		version string
+		err     error
+	)
+
+	switch ch {
+	case channelPrerelease:
+		version, err = vr.resolveHighestTagged(ctx, mod)
+	case channelTip:
+		version, err = vr.resolveTip(ctx, mod)
+	default:
+		version, err = vr.resolveStable(ctx, mod)
+	}
+
+	vr.mu.Lock()
+	vr.cache[key] = resolvedVersion{version: version, err: err}
+	vr.mu.Unlock()
+
+	return version, err
+}
+
+func (vr *versionResolver) cached(key string) (version string, err error, ok bool) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	cached, ok := vr.cache[key]
+
+	return cached.version, cached.err, ok
+}
+
+func (vr *versionResolver) resolveStable(ctx context.Context, mod string) (string, error) {
+	var errs error
+
+	for _, proxy := range vr.proxies {
+		if proxy == "off" {
+			break
+		}
+
+		var (
+			version string
+			err     error
+		)
+
+		if proxy == "direct" {
+			var ver module.Version
+			ver, err = modproxy.GetLatestVersion(ctx, mod)
+			version = ver.Version
+		} else {
+			version, err = vr.queryProxy(ctx, proxy, mod)
+		}
+
+		if err == nil {
+			return version, nil
+		}
+
+		errs = multierror.Append(errs, fmt.Errorf("%v: %w", proxy, err))
+	}
+
+	if errs == nil {
+		return "", fmt.Errorf("goreinstall: GOPROXY is \"off\", cannot resolve latest version of (%v)", mod)
+	}
+
+	return "", fmt.Errorf("could not resolve latest version of (%v) from any configured proxy (%w)", mod, errs)
+}
+
+// proxyLatestVersion is the body of a GOPROXY "@latest" response, per
+// https://go.dev/ref/mod#goproxy-protocol.
+type proxyLatestVersion struct {
+	Version string
+}
+
+func (vr *versionResolver) queryProxy(ctx context.Context, proxyURL, mod string) (string, error) {
+	escapedMod, err := module.EscapePath(mod)
+	if err != nil {
+		return "", fmt.Errorf("could not escape module path (%v) error (%w)", mod, err)
+	}
+
+	u := strings.TrimRight(proxyURL, "/") + "/" + escapedMod + "/@latest"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not build request for (%v) error (%w)", u, err)
+	}
+
+	resp, err := vr.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not GET (%v) error (%w)", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("goreinstall: proxy (%v) returned status (%v) for (%v)", proxyURL, resp.Status, mod)
+	}
+
+	lv := proxyLatestVersion{}
+	if err = json.NewDecoder(resp.Body).Decode(&lv); err != nil {
+		return "", fmt.Errorf("could not decode @latest response from (%v) error (%w)", u, err)
+	}
+
+	return lv.Version, nil
+}
+
+// resolveHighestTagged implements channelPrerelease: it queries every
+// tagged version of mod via the GOPROXY "@v/list" protocol and returns the
+// highest one by semver, including prerelease tags such as -rc.1 or -beta.
+// "direct" entries of the GOPROXY chain are skipped.
+func (vr *versionResolver) resolveHighestTagged(ctx context.Context, mod string) (string, error) {
+	var errs error
+
+	for _, proxy := range vr.proxies {
+		if proxy == "off" {
+			break
+		}
+		if proxy == "direct" {
+			continue
+		}
+
+		versions, err := vr.queryProxyList(ctx, proxy, mod)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%v: %w", proxy, err))
+			continue
+		}
+
+		var highest string
+		for _, v := range versions {
+			if highest == "" || semver.Compare(v, highest) == 1 {
+				highest = v
+			}
+		}
+
+		if highest == "" {
+			errs = multierror.Append(errs, fmt.Errorf("%v: goreinstall: no tagged versions of (%v)", proxy, mod))
+			continue
+		}
+
+		return highest, nil
+	}
+
+	if errs == nil {
+		return "", fmt.Errorf("goreinstall: no usable proxy configured to list tagged versions of (%v)", mod)
+	}
+
+	return "", fmt.Errorf("could not list tagged versions of (%v) from any configured proxy (%w)", mod, errs)
+}
+
+func (vr *versionResolver) queryProxyList(ctx context.Context, proxyURL, mod string) ([]string, error) {
+	escapedMod, err := module.EscapePath(mod)
+	if err != nil {
+		return nil, fmt.Errorf("could not escape module path (%v) error (%w)", mod, err)
+	}
+
+	u := strings.TrimRight(proxyURL, "/") + "/" + escapedMod + "/@v/list"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not build request for (%v) error (%w)", u, err)
+	}
+
+	resp, err := vr.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not GET (%v) error (%w)", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("goreinstall: proxy (%v) returned status (%v) for (%v)", proxyURL, resp.Status, mod)
+	}
+
+	body := &bytes.Buffer{}
+	if _, err = body.ReadFrom(resp.Body); err != nil {
+		return nil, fmt.Errorf("could not read @v/list response from (%v) error (%w)", u, err)
+	}
+
+	var versions []string
+	for _, line := range strings.Split(body.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			versions = append(versions, line)
+		}
+	}
+
+	return versions, nil
+}
+
+// resolveTip implements channelTip: it shells out to "go list -m -json
+// mod@latest" using vr.compilerPath. For a module with no tagged releases
+// that is a pseudo-version of its latest commit; for a module with tagged
+// releases it is the latest release, not any later untagged commit.
+func (vr *versionResolver) resolveTip(ctx context.Context, mod string) (string, error) {
+	compilerPath := vr.compilerPath
+	if compilerPath == "" {
+		compilerPath = "go"
+	}
+
+	// #nosec G204
+	cmd := exec.CommandContext(ctx, compilerPath, "list", "-m", "-json", mod+"@latest")
+	cmd.Stderr = os.Stderr
+
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("could not run (go list -m -json %v@latest) due to error (%w)", mod, err)
+	}
+
+	info := struct {
+		Version string
+	}{}
+
+	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+		return "", fmt.Errorf("could not parse (go list -m -json %v@latest) output due to error (%w)", mod, err)
+	}
+
+	return info.Version, nil
+}